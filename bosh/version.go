@@ -0,0 +1,63 @@
+package bosh
+
+import (
+	"strconv"
+	"strings"
+)
+
+// BOSHVersionError indicates that the installed bosh-cli's version could
+// not be determined, for example because it is a dev build.
+type BOSHVersionError struct {
+	err error
+}
+
+func NewBOSHVersionError(err error) BOSHVersionError {
+	return BOSHVersionError{err: err}
+}
+
+func (b BOSHVersionError) Error() string {
+	return b.err.Error()
+}
+
+// IsBOSHVersionAtLeast returns whether actualVersion is greater than or
+// equal to minimumVersion, comparing dot-separated version parts
+// numerically.
+func IsBOSHVersionAtLeast(minimumVersion, actualVersion string) (bool, error) {
+	minimumVersionParts, err := versionParts(minimumVersion)
+	if err != nil {
+		return false, err
+	}
+
+	actualVersionParts, err := versionParts(actualVersion)
+	if err != nil {
+		return false, err
+	}
+
+	for i := range minimumVersionParts {
+		if i >= len(actualVersionParts) {
+			return false, nil
+		}
+
+		if actualVersionParts[i] > minimumVersionParts[i] {
+			return true, nil
+		}
+
+		if actualVersionParts[i] < minimumVersionParts[i] {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+func versionParts(version string) ([]int, error) {
+	parts := []int{}
+	for _, part := range strings.Split(version, ".") {
+		number, err := strconv.Atoi(part)
+		if err != nil {
+			return nil, err
+		}
+		parts = append(parts, number)
+	}
+	return parts, nil
+}
@@ -0,0 +1,572 @@
+package commands
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/cloudfoundry/bosh-bootloader/bosh"
+	"github.com/cloudfoundry/bosh-bootloader/config"
+	"github.com/cloudfoundry/bosh-bootloader/storage"
+)
+
+const MinimumBOSHVersion = "2.0.24"
+
+type boshManager interface {
+	Version() (string, error)
+	InitializeJumpbox(storage.State) error
+	InitializeDirector(storage.State) error
+}
+
+type terraformManager interface {
+	Init(storage.State) error
+	ValidateVersion() error
+}
+
+type cloudConfigManager interface {
+	Initialize(storage.State) error
+}
+
+type stateStore interface {
+	Set(storage.State) error
+	GetBblDir() (string, error)
+}
+
+type envIDManager interface {
+	Sync(storage.State, string) (storage.State, error)
+}
+
+// journal records the state of each step of a `bbl plan` run so that a run
+// interrupted partway through can be resumed without repeating completed
+// steps.
+type journal interface {
+	Task(name string) (storage.PlanTask, bool)
+	Tasks() ([]storage.PlanTask, error)
+	Record(name string, status string, recordErr error, state string) error
+}
+
+// ConfigLoader loads a declarative bbl.yml/bbl.json config file, resolving
+// any relative ops file paths it contains against the config file's own
+// directory.
+type ConfigLoader interface {
+	Load(path string) (config.PlanConfigFile, error)
+}
+
+// logger writes a structured audit line for each step of a `bbl plan` run.
+type logger interface {
+	Configure(path string, level string) error
+	SetSecrets(secrets []string)
+	Step(name string, start time.Time, stepErr error) error
+}
+
+const (
+	planStepSyncEnvID          = "sync-env-id"
+	planStepSaveState          = "save-state"
+	planStepTerraformInit      = "terraform-init"
+	planStepInitializeJumpbox  = "initialize-jumpbox"
+	planStepInitializeDirector = "initialize-director"
+	planStepCloudConfigInit    = "cloud-config-init"
+)
+
+type PlanConfig struct {
+	Name          string
+	NoDirector    bool
+	OpsFiles      []string
+	Force         bool
+	Status        bool
+	IAAS          string
+	LB            config.LBConfig
+	TerraformVars map[string]string
+	LogLevel      string
+	LogFile       string
+}
+
+type Plan struct {
+	boshManager        boshManager
+	cloudConfigManager cloudConfigManager
+	stateStore         stateStore
+	envIDManager       envIDManager
+	terraformManager   terraformManager
+	journal            journal
+	configLoader       ConfigLoader
+	logger             logger
+}
+
+func NewPlan(boshManager boshManager, cloudConfigManager cloudConfigManager, stateStore stateStore, envIDManager envIDManager, terraformManager terraformManager, journal journal, configLoader ConfigLoader, logger logger) Plan {
+	return Plan{
+		boshManager:        boshManager,
+		cloudConfigManager: cloudConfigManager,
+		stateStore:         stateStore,
+		envIDManager:       envIDManager,
+		terraformManager:   terraformManager,
+		journal:            journal,
+		configLoader:       configLoader,
+		logger:             logger,
+	}
+}
+
+func (p Plan) CheckFastFails(args []string, state storage.State) error {
+	planConfig, err := p.ParseArgs(args, state)
+	if err != nil {
+		return err
+	}
+
+	if err := p.configureLogger(planConfig, state); err != nil {
+		return err
+	}
+
+	err = p.logStep("validate-terraform-version", func() error {
+		return p.terraformManager.ValidateVersion()
+	})
+	if err != nil {
+		return fmt.Errorf("Terraform manager validate version: %s", err)
+	}
+
+	if planConfig.Name != "" && state.EnvID != "" && planConfig.Name != state.EnvID {
+		return fmt.Errorf("The director name cannot be changed for an existing environment. Current name is %s.", state.EnvID)
+	}
+
+	if planConfig.NoDirector {
+		return nil
+	}
+
+	var version string
+	err = p.logStep("check-bosh-version", func() error {
+		var versionErr error
+		version, versionErr = p.boshManager.Version()
+		return versionErr
+	})
+	switch err.(type) {
+	case bosh.BOSHVersionError:
+		return nil
+	case nil:
+	default:
+		return err
+	}
+
+	valid, err := bosh.IsBOSHVersionAtLeast(MinimumBOSHVersion, version)
+	if err != nil {
+		return err
+	}
+
+	if !valid {
+		return errors.New("BOSH version must be at least v2.0.24")
+	}
+
+	return nil
+}
+
+func (p Plan) ParseArgs(args []string, state storage.State) (PlanConfig, error) {
+	planConfig := PlanConfig{}
+	var opsFileFlags multiStringFlag
+	var configPath string
+
+	planFlags := flag.NewFlagSet("plan", flag.ContinueOnError)
+	planFlags.SetOutput(ioutil.Discard)
+	planFlags.StringVar(&planConfig.Name, "name", "", "")
+	planFlags.BoolVar(&planConfig.NoDirector, "no-director", false, "")
+	planFlags.Var(&opsFileFlags, "ops-file", "")
+	planFlags.BoolVar(&planConfig.Force, "force", false, "")
+	planFlags.BoolVar(&planConfig.Status, "status", false, "")
+	planFlags.StringVar(&configPath, "config", "", "")
+	planFlags.StringVar(&planConfig.LogLevel, "log-level", "", "")
+	planFlags.StringVar(&planConfig.LogFile, "log-file", "", "")
+
+	err := planFlags.Parse(args)
+	if err != nil {
+		return PlanConfig{}, err
+	}
+
+	explicitFlags := map[string]bool{}
+	planFlags.Visit(func(f *flag.Flag) {
+		explicitFlags[f.Name] = true
+	})
+
+	rawOpsFiles := []string(opsFileFlags)
+
+	if configPath != "" {
+		configFile, err := p.configLoader.Load(configPath)
+		if err != nil {
+			return PlanConfig{}, fmt.Errorf("Load config file: %s", err)
+		}
+
+		if !explicitFlags["name"] {
+			planConfig.Name = configFile.Name
+		}
+		if !explicitFlags["no-director"] {
+			planConfig.NoDirector = configFile.NoDirector
+		}
+		if len(rawOpsFiles) == 0 {
+			rawOpsFiles = configFile.OpsFiles
+		}
+
+		planConfig.IAAS = configFile.IAAS
+		planConfig.LB = configFile.LB
+		planConfig.TerraformVars = configFile.TerraformVars
+	}
+
+	if !planConfig.NoDirector {
+		planConfig.NoDirector = state.NoDirector
+	}
+
+	if len(rawOpsFiles) == 0 {
+		opsFiles, err := p.writeOpsFiles(userOpsFileContents(state))
+		if err != nil {
+			return PlanConfig{}, err
+		}
+		planConfig.OpsFiles = opsFiles
+		return planConfig, nil
+	}
+
+	workingDir, err := os.Getwd()
+	if err != nil {
+		return PlanConfig{}, fmt.Errorf("Get working directory: %s", err)
+	}
+
+	contents := make([]string, len(rawOpsFiles))
+	for i, rawPath := range rawOpsFiles {
+		fileContents, err := readOpsFile(rawPath, workingDir)
+		if err != nil {
+			return PlanConfig{}, fmt.Errorf("Read ops file %q: %s", rawPath, err)
+		}
+		contents[i] = fileContents
+	}
+
+	opsFiles, err := p.writeOpsFiles(contents)
+	if err != nil {
+		return PlanConfig{}, err
+	}
+	planConfig.OpsFiles = opsFiles
+
+	return planConfig, nil
+}
+
+func (p Plan) Execute(args []string, state storage.State) error {
+	planConfig, err := p.ParseArgs(args, state)
+	if err != nil {
+		return err
+	}
+
+	if planConfig.Status {
+		return p.renderStatus()
+	}
+
+	if err := p.configureLogger(planConfig, state); err != nil {
+		return err
+	}
+
+	if planConfig.NoDirector && (state.BOSH.DirectorUsername != "" || state.BOSH.DirectorPassword != "") {
+		return errors.New(`Director already exists, you must re-create your environment to use "--no-director"`)
+	}
+
+	state, err = p.runSyncEnvID(planConfig, state)
+	if err != nil {
+		return fmt.Errorf("Sync env ID: %s", err)
+	}
+
+	if planConfig.IAAS != "" {
+		state.IAAS = planConfig.IAAS
+	}
+	if planConfig.LB != (config.LBConfig{}) {
+		state.LB = storage.LB{
+			Type:   planConfig.LB.Type,
+			Domain: planConfig.LB.Domain,
+			Cert:   planConfig.LB.Cert,
+			Key:    planConfig.LB.Key,
+		}
+	}
+	if len(planConfig.TerraformVars) > 0 {
+		state.TFVars = planConfig.TerraformVars
+	}
+
+	if len(planConfig.OpsFiles) > 0 {
+		opsFileContents := make([]string, len(planConfig.OpsFiles))
+		for i, path := range planConfig.OpsFiles {
+			contents, err := ioutil.ReadFile(path)
+			if err != nil {
+				return fmt.Errorf("Read ops file: %s", err)
+			}
+			opsFileContents[i] = string(contents)
+		}
+		state.BOSH.UserOpsFiles = opsFileContents
+	}
+
+	err = p.runStep(planStepSaveState, planConfig.Force, func() error {
+		return p.stateStore.Set(state)
+	})
+	if err != nil {
+		return fmt.Errorf("Save state: %s", err)
+	}
+
+	err = p.runStep(planStepTerraformInit, planConfig.Force, func() error {
+		return p.terraformManager.Init(state)
+	})
+	if err != nil {
+		return fmt.Errorf("Terraform manager init: %s", err)
+	}
+
+	if !planConfig.NoDirector {
+		err = p.runStep(planStepInitializeJumpbox, planConfig.Force, func() error {
+			return p.boshManager.InitializeJumpbox(state)
+		})
+		if err != nil {
+			return fmt.Errorf("Bosh manager initialize jumpbox: %s", err)
+		}
+
+		err = p.runStep(planStepInitializeDirector, planConfig.Force, func() error {
+			return p.boshManager.InitializeDirector(state)
+		})
+		if err != nil {
+			return fmt.Errorf("Bosh manager initialize director: %s", err)
+		}
+	}
+
+	err = p.runStep(planStepCloudConfigInit, planConfig.Force, func() error {
+		return p.cloudConfigManager.Initialize(state)
+	})
+	if err != nil {
+		return fmt.Errorf("Cloud config manager initialize: %s", err)
+	}
+
+	return nil
+}
+
+// runStep executes fn unless the journal already records name as done and
+// force is false, recording the step's running/done/failed status in the
+// journal as it goes, and writing a structured audit line for the attempt.
+func (p Plan) runStep(name string, force bool, fn func() error) error {
+	if !force {
+		if task, ok := p.journal.Task(name); ok && task.Status == storage.PlanTaskStatusDone {
+			return nil
+		}
+	}
+
+	if err := p.journal.Record(name, storage.PlanTaskStatusRunning, nil, ""); err != nil {
+		return err
+	}
+
+	return p.logStep(name, func() error {
+		if err := fn(); err != nil {
+			p.journal.Record(name, storage.PlanTaskStatusFailed, err, "")
+			return err
+		}
+
+		return p.journal.Record(name, storage.PlanTaskStatusDone, nil, "")
+	})
+}
+
+// runSyncEnvID wraps envIDManager.Sync like runStep, but additionally
+// journals the resulting state itself (rather than just a "done" marker).
+// sync-env-id runs before save-state persists its result to disk, so if
+// save-state then fails, a plain "done" marker would cause the next run to
+// skip re-deriving the synced env ID and instead save-state the stale,
+// un-synced state it was given. Journaling the synced state lets a skip
+// restore it instead.
+func (p Plan) runSyncEnvID(planConfig PlanConfig, state storage.State) (storage.State, error) {
+	name := planStepSyncEnvID
+
+	if !planConfig.Force {
+		if task, ok := p.journal.Task(name); ok && task.Status == storage.PlanTaskStatusDone && task.State != "" {
+			var synced storage.State
+			if err := json.Unmarshal([]byte(task.State), &synced); err != nil {
+				return state, fmt.Errorf("Unmarshal journaled state: %s", err)
+			}
+			return synced, nil
+		}
+	}
+
+	if err := p.journal.Record(name, storage.PlanTaskStatusRunning, nil, ""); err != nil {
+		return state, err
+	}
+
+	var synced storage.State
+	err := p.logStep(name, func() error {
+		var syncErr error
+		synced, syncErr = p.envIDManager.Sync(state, planConfig.Name)
+		return syncErr
+	})
+	if err != nil {
+		p.journal.Record(name, storage.PlanTaskStatusFailed, err, "")
+		return state, err
+	}
+
+	encoded, err := json.Marshal(synced)
+	if err != nil {
+		return state, fmt.Errorf("Marshal synced state: %s", err)
+	}
+
+	if err := p.journal.Record(name, storage.PlanTaskStatusDone, nil, string(encoded)); err != nil {
+		return state, err
+	}
+
+	return synced, nil
+}
+
+// logStep runs fn and writes a structured audit line recording how long it
+// took and whether it succeeded.
+func (p Plan) logStep(name string, fn func() error) error {
+	start := time.Now()
+	err := fn()
+	if logErr := p.logger.Step(name, start, err); logErr != nil {
+		if err == nil {
+			return fmt.Errorf("Write log: %s", logErr)
+		}
+	}
+	return err
+}
+
+// configureLogger points the logger at the configured (or default) log file
+// and registers director credentials as secrets to redact from it.
+func (p Plan) configureLogger(planConfig PlanConfig, state storage.State) error {
+	logFile := planConfig.LogFile
+	if logFile == "" {
+		bblDir, err := p.stateStore.GetBblDir()
+		if err != nil {
+			return fmt.Errorf("Get bbl dir: %s", err)
+		}
+		logFile = filepath.Join(bblDir, "bbl.log")
+	}
+
+	if err := p.logger.Configure(logFile, planConfig.LogLevel); err != nil {
+		return fmt.Errorf("Configure logger: %s", err)
+	}
+
+	p.logger.SetSecrets([]string{
+		state.BOSH.DirectorPassword,
+		state.BOSH.DirectorSSLPrivateKey,
+		state.BOSH.DirectorSSLCA,
+		state.BOSH.DirectorSSLCertificate,
+	})
+
+	return nil
+}
+
+func (p Plan) renderStatus() error {
+	tasks, err := p.journal.Tasks()
+	if err != nil {
+		return fmt.Errorf("Read plan journal: %s", err)
+	}
+
+	if len(tasks) == 0 {
+		fmt.Println("No plan has been run yet.")
+		return nil
+	}
+
+	for _, task := range tasks {
+		if task.Error != "" {
+			fmt.Printf("%s\t%s\t%s\t%s\n", task.Name, task.Status, task.UpdatedAt, task.Error)
+			continue
+		}
+		fmt.Printf("%s\t%s\t%s\n", task.Name, task.Status, task.UpdatedAt)
+	}
+
+	return nil
+}
+
+// userOpsFileContents returns the ops file contents previously persisted on
+// state, migrating the legacy single UserOpsFile into the ordered
+// UserOpsFiles list when necessary.
+func userOpsFileContents(state storage.State) []string {
+	if len(state.BOSH.UserOpsFiles) > 0 {
+		return state.BOSH.UserOpsFiles
+	}
+
+	if state.BOSH.UserOpsFile != "" {
+		return []string{state.BOSH.UserOpsFile}
+	}
+
+	return []string{}
+}
+
+// writeOpsFiles writes each ops file's contents into the bbl dir using a
+// stable, ordered name so that re-running `bbl plan` reuses them in the
+// same order.
+func (p Plan) writeOpsFiles(contents []string) ([]string, error) {
+	bblDir, err := p.stateStore.GetBblDir()
+	if err != nil {
+		return nil, fmt.Errorf("Get bbl dir: %s", err)
+	}
+
+	opsFiles := make([]string, len(contents))
+	for i, c := range contents {
+		destination := filepath.Join(bblDir, fmt.Sprintf("user-ops-file-%d.yml", i))
+		err := ioutil.WriteFile(destination, []byte(c), storage.StateMode)
+		if err != nil {
+			return nil, fmt.Errorf("Write ops file %q: %s", destination, err)
+		}
+		opsFiles[i] = destination
+	}
+
+	return opsFiles, nil
+}
+
+// readOpsFile resolves raw (as provided to --ops-file) and returns its
+// contents. Strings starting with "http" are fetched over HTTP; "file://"
+// URIs and absolute paths are used verbatim; "~" is expanded to the user's
+// home directory; anything else is resolved relative to workingDir.
+func readOpsFile(raw string, workingDir string) (string, error) {
+	if strings.HasPrefix(raw, "http") {
+		resp, err := http.Get(raw)
+		if err != nil {
+			return "", err
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			return "", fmt.Errorf("unexpected status code %d fetching %q", resp.StatusCode, raw)
+		}
+
+		body, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			return "", err
+		}
+		return string(body), nil
+	}
+
+	path, err := resolveOpsFilePath(raw, workingDir)
+	if err != nil {
+		return "", err
+	}
+
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return string(contents), nil
+}
+
+func resolveOpsFilePath(raw string, workingDir string) (string, error) {
+	switch {
+	case strings.HasPrefix(raw, "file://"):
+		return strings.TrimPrefix(raw, "file://"), nil
+	case filepath.IsAbs(raw):
+		return raw, nil
+	case strings.HasPrefix(raw, "~"):
+		usr, err := user.Current()
+		if err != nil {
+			return "", err
+		}
+		return filepath.Join(usr.HomeDir, strings.TrimPrefix(raw, "~")), nil
+	default:
+		return filepath.Join(workingDir, raw), nil
+	}
+}
+
+type multiStringFlag []string
+
+func (m *multiStringFlag) String() string {
+	return strings.Join(*m, ",")
+}
+
+func (m *multiStringFlag) Set(value string) error {
+	*m = append(*m, value)
+	return nil
+}
@@ -3,11 +3,15 @@ package commands_test
 import (
 	"errors"
 	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"os/user"
 	"path/filepath"
 
 	"github.com/cloudfoundry/bosh-bootloader/bosh"
 	"github.com/cloudfoundry/bosh-bootloader/commands"
+	bblconfig "github.com/cloudfoundry/bosh-bootloader/config"
 	"github.com/cloudfoundry/bosh-bootloader/fakes"
 	"github.com/cloudfoundry/bosh-bootloader/storage"
 
@@ -24,6 +28,9 @@ var _ = Describe("Plan", func() {
 		cloudConfigManager *fakes.CloudConfigManager
 		stateStore         *fakes.StateStore
 		envIDManager       *fakes.EnvIDManager
+		journal            *fakes.Journal
+		configLoader       *fakes.ConfigLoader
+		logger             *fakes.Logger
 
 		tempDir string
 	)
@@ -36,6 +43,9 @@ var _ = Describe("Plan", func() {
 		cloudConfigManager = &fakes.CloudConfigManager{}
 		stateStore = &fakes.StateStore{}
 		envIDManager = &fakes.EnvIDManager{}
+		journal = &fakes.Journal{}
+		configLoader = &fakes.ConfigLoader{}
+		logger = &fakes.Logger{}
 
 		var err error
 		tempDir, err = ioutil.TempDir("", "")
@@ -43,7 +53,7 @@ var _ = Describe("Plan", func() {
 
 		stateStore.GetBblDirCall.Returns.Directory = tempDir
 
-		command = commands.NewPlan(boshManager, cloudConfigManager, stateStore, envIDManager, terraformManager)
+		command = commands.NewPlan(boshManager, cloudConfigManager, stateStore, envIDManager, terraformManager, journal, configLoader, logger)
 	})
 
 	Describe("Execute", func() {
@@ -105,6 +115,49 @@ var _ = Describe("Plan", func() {
 			})
 		})
 
+		Context("when --ops-file flags are passed", func() {
+			It("persists the resolved ops files, in order, onto the state passed to terraform and bosh managers", func() {
+				opsFileDir, err := ioutil.TempDir("", "")
+				Expect(err).NotTo(HaveOccurred())
+
+				opsFilePath := filepath.Join(opsFileDir, "some-ops-file")
+				Expect(ioutil.WriteFile(opsFilePath, []byte("some-ops-file-contents"), os.ModePerm)).To(Succeed())
+
+				err = command.Execute([]string{"--ops-file", opsFilePath}, state)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(terraformManager.InitCall.Receives.BBLState.BOSH.UserOpsFiles).To(Equal([]string{"some-ops-file-contents"}))
+				Expect(boshManager.InitializeDirectorCall.Receives.State.BOSH.UserOpsFiles).To(Equal([]string{"some-ops-file-contents"}))
+			})
+		})
+
+		Context("when a --config file sets iaas, lb, and terraform_vars", func() {
+			It("persists them onto the state passed to the terraform and bosh managers", func() {
+				configLoader.LoadCall.Returns.File = bblconfig.PlanConfigFile{
+					IAAS: "gcp",
+					LB: bblconfig.LBConfig{
+						Type:   "cf",
+						Domain: "some-domain",
+						Cert:   "some-cert",
+						Key:    "some-key",
+					},
+					TerraformVars: map[string]string{"some-var": "some-value"},
+				}
+
+				err := command.Execute([]string{"--config", "/some/bbl.yml"}, state)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(terraformManager.InitCall.Receives.BBLState.IAAS).To(Equal("gcp"))
+				Expect(terraformManager.InitCall.Receives.BBLState.LB).To(Equal(storage.LB{
+					Type:   "cf",
+					Domain: "some-domain",
+					Cert:   "some-cert",
+					Key:    "some-key",
+				}))
+				Expect(terraformManager.InitCall.Receives.BBLState.TFVars).To(Equal(map[string]string{"some-var": "some-value"}))
+			})
+		})
+
 		Describe("failure cases", func() {
 			It("returns an error if state store set fails", func() {
 				stateStore.SetCall.Returns = []fakes.SetCallReturn{{Error: errors.New("peach")}}
@@ -141,6 +194,132 @@ var _ = Describe("Plan", func() {
 				Expect(err).To(MatchError("Cloud config manager initialize: potato"))
 			})
 		})
+
+		Describe("resuming a partial plan", func() {
+			It("skips steps the journal already records as done, restoring any journaled state", func() {
+				journal.TaskCall.Returns = map[string]fakes.JournalTaskReturn{
+					"sync-env-id": {
+						Task: storage.PlanTask{
+							Status: storage.PlanTaskStatusDone,
+							State:  `{"ID":"synced-state-id"}`,
+						},
+						Ok: true,
+					},
+				}
+
+				err := command.Execute([]string{}, state)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(envIDManager.SyncCall.CallCount).To(Equal(0))
+				Expect(stateStore.SetCall.CallCount).To(Equal(1))
+				Expect(stateStore.SetCall.Receives[0].State).To(Equal(storage.State{ID: "synced-state-id"}))
+			})
+
+			It("does not skip sync-env-id when it's recorded done without a journaled state", func() {
+				journal.TaskCall.Returns = map[string]fakes.JournalTaskReturn{
+					"sync-env-id": {Task: storage.PlanTask{Status: storage.PlanTaskStatusDone}, Ok: true},
+				}
+
+				err := command.Execute([]string{}, state)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(envIDManager.SyncCall.CallCount).To(Equal(1))
+			})
+
+			Context("when --force is passed", func() {
+				It("re-runs every step, even ones the journal records as done", func() {
+					journal.TaskCall.Returns = map[string]fakes.JournalTaskReturn{
+						"sync-env-id": {Task: storage.PlanTask{Status: storage.PlanTaskStatusDone}, Ok: true},
+					}
+
+					err := command.Execute([]string{"--force"}, state)
+					Expect(err).NotTo(HaveOccurred())
+
+					Expect(envIDManager.SyncCall.CallCount).To(Equal(1))
+				})
+			})
+
+			It("records a failed step in the journal", func() {
+				terraformManager.InitCall.Returns.Error = errors.New("pomegranate")
+
+				err := command.Execute([]string{}, state)
+				Expect(err).To(MatchError("Terraform manager init: pomegranate"))
+
+				recorded := journal.RecordCall.Receives
+				Expect(recorded[len(recorded)-1]).To(Equal(fakes.JournalRecordReceive{
+					Name:   "terraform-init",
+					Status: storage.PlanTaskStatusFailed,
+					Error:  errors.New("pomegranate"),
+				}))
+			})
+		})
+
+		Describe("--status", func() {
+			It("renders the journal without running any plan steps", func() {
+				journal.TasksCall.Returns.Tasks = []storage.PlanTask{
+					{Name: "sync-env-id", Status: storage.PlanTaskStatusDone, UpdatedAt: "2020-01-01T00:00:00Z"},
+				}
+
+				err := command.Execute([]string{"--status"}, state)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(journal.TasksCall.CallCount).To(Equal(1))
+				Expect(envIDManager.SyncCall.CallCount).To(Equal(0))
+				Expect(stateStore.SetCall.CallCount).To(Equal(0))
+			})
+		})
+
+		Describe("logging", func() {
+			It("configures the logger with the bbl dir's default log file", func() {
+				err := command.Execute([]string{}, state)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(logger.ConfigureCall.CallCount).To(Equal(1))
+				Expect(logger.ConfigureCall.Receives.Path).To(Equal(filepath.Join(tempDir, "bbl.log")))
+				Expect(logger.ConfigureCall.Receives.Level).To(Equal(""))
+			})
+
+			Context("when --log-file and --log-level are passed", func() {
+				It("configures the logger with the given path and level", func() {
+					err := command.Execute([]string{"--log-file", "/some/custom.log", "--log-level", "debug"}, state)
+					Expect(err).NotTo(HaveOccurred())
+
+					Expect(logger.ConfigureCall.Receives.Path).To(Equal("/some/custom.log"))
+					Expect(logger.ConfigureCall.Receives.Level).To(Equal("debug"))
+				})
+			})
+
+			It("registers the director's credentials as secrets to redact", func() {
+				state.BOSH.DirectorPassword = "some-password"
+
+				err := command.Execute([]string{}, state)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(logger.SetSecretsCall.Receives.Secrets).To(ContainElement("some-password"))
+			})
+
+			It("writes a step line for each step it runs", func() {
+				err := command.Execute([]string{}, state)
+				Expect(err).NotTo(HaveOccurred())
+
+				names := []string{}
+				for _, receive := range logger.StepCall.Receives {
+					names = append(names, receive.Name)
+				}
+				Expect(names).To(ContainElement("sync-env-id"))
+				Expect(names).To(ContainElement("terraform-init"))
+				Expect(names).To(ContainElement("cloud-config-init"))
+			})
+
+			Context("when the logger fails to configure", func() {
+				It("returns an error", func() {
+					logger.ConfigureCall.Returns.Error = errors.New("fig")
+
+					err := command.Execute([]string{}, state)
+					Expect(err).To(MatchError("Configure logger: fig"))
+				})
+			})
+		})
 	})
 
 	Describe("CheckFastFails", func() {
@@ -222,7 +401,7 @@ var _ = Describe("Plan", func() {
 	})
 
 	Describe("ParseArgs", func() {
-		Context("when the --ops-file flag is specified", func() {
+		Context("when a single --ops-file flag is specified", func() {
 			var providedOpsFilePath string
 			BeforeEach(func() {
 				opsFileDir, err := ioutil.TempDir("", "")
@@ -234,13 +413,124 @@ var _ = Describe("Plan", func() {
 				Expect(err).NotTo(HaveOccurred())
 			})
 
-			It("returns a config with the ops-file path", func() {
+			It("returns a config with the resolved ops-file copied into the bbl dir", func() {
 				config, err := command.ParseArgs([]string{
 					"--ops-file", providedOpsFilePath,
 				}, storage.State{})
 				Expect(err).NotTo(HaveOccurred())
 
-				Expect(config.OpsFile).To(Equal(providedOpsFilePath))
+				Expect(config.OpsFiles).To(Equal([]string{filepath.Join(tempDir, "user-ops-file-0.yml")}))
+
+				contents, err := ioutil.ReadFile(config.OpsFiles[0])
+				Expect(err).NotTo(HaveOccurred())
+				Expect(string(contents)).To(Equal("some-ops-file-contents"))
+			})
+		})
+
+		Context("when multiple --ops-file flags are specified", func() {
+			var opsFileOnePath, opsFileTwoPath string
+
+			BeforeEach(func() {
+				opsFileDir, err := ioutil.TempDir("", "")
+				Expect(err).NotTo(HaveOccurred())
+
+				opsFileOnePath = filepath.Join(opsFileDir, "ops-file-one")
+				opsFileTwoPath = filepath.Join(opsFileDir, "ops-file-two")
+
+				Expect(ioutil.WriteFile(opsFileOnePath, []byte("ops-file-one-contents"), os.ModePerm)).To(Succeed())
+				Expect(ioutil.WriteFile(opsFileTwoPath, []byte("ops-file-two-contents"), os.ModePerm)).To(Succeed())
+			})
+
+			It("resolves and copies each ops-file into the bbl dir, in order", func() {
+				config, err := command.ParseArgs([]string{
+					"--ops-file", opsFileOnePath,
+					"--ops-file", opsFileTwoPath,
+				}, storage.State{})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(config.OpsFiles).To(Equal([]string{
+					filepath.Join(tempDir, "user-ops-file-0.yml"),
+					filepath.Join(tempDir, "user-ops-file-1.yml"),
+				}))
+
+				firstContents, err := ioutil.ReadFile(config.OpsFiles[0])
+				Expect(err).NotTo(HaveOccurred())
+				Expect(string(firstContents)).To(Equal("ops-file-one-contents"))
+
+				secondContents, err := ioutil.ReadFile(config.OpsFiles[1])
+				Expect(err).NotTo(HaveOccurred())
+				Expect(string(secondContents)).To(Equal("ops-file-two-contents"))
+			})
+		})
+
+		Context("when an --ops-file is a file:// URI", func() {
+			It("resolves the path verbatim", func() {
+				opsFileDir, err := ioutil.TempDir("", "")
+				Expect(err).NotTo(HaveOccurred())
+
+				opsFilePath := filepath.Join(opsFileDir, "some-ops-file")
+				Expect(ioutil.WriteFile(opsFilePath, []byte("file-uri-contents"), os.ModePerm)).To(Succeed())
+
+				config, err := command.ParseArgs([]string{
+					"--ops-file", "file://" + opsFilePath,
+				}, storage.State{})
+				Expect(err).NotTo(HaveOccurred())
+
+				contents, err := ioutil.ReadFile(config.OpsFiles[0])
+				Expect(err).NotTo(HaveOccurred())
+				Expect(string(contents)).To(Equal("file-uri-contents"))
+			})
+		})
+
+		Context("when an --ops-file is an http(s) URL", func() {
+			It("fetches the contents over HTTP", func() {
+				server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					w.Write([]byte("http-ops-file-contents"))
+				}))
+				defer server.Close()
+
+				config, err := command.ParseArgs([]string{
+					"--ops-file", server.URL,
+				}, storage.State{})
+				Expect(err).NotTo(HaveOccurred())
+
+				contents, err := ioutil.ReadFile(config.OpsFiles[0])
+				Expect(err).NotTo(HaveOccurred())
+				Expect(string(contents)).To(Equal("http-ops-file-contents"))
+			})
+
+			Context("when the server returns a non-200 status code", func() {
+				It("returns an error", func() {
+					server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+						w.WriteHeader(http.StatusNotFound)
+					}))
+					defer server.Close()
+
+					_, err := command.ParseArgs([]string{
+						"--ops-file", server.URL,
+					}, storage.State{})
+					Expect(err).To(MatchError(ContainSubstring("unexpected status code 404")))
+				})
+			})
+		})
+
+		Context("when an --ops-file starts with ~", func() {
+			It("expands ~ to the user's home directory", func() {
+				usr, err := user.Current()
+				Expect(err).NotTo(HaveOccurred())
+
+				opsFilePath := filepath.Join(usr.HomeDir, "bbl-plan-test-ops-file")
+				Expect(ioutil.WriteFile(opsFilePath, []byte("tilde-contents"), os.ModePerm)).To(Succeed())
+				defer os.Remove(opsFilePath)
+
+				config, err := command.ParseArgs([]string{
+					"--ops-file", "~/bbl-plan-test-ops-file",
+				}, storage.State{})
+				Expect(err).NotTo(HaveOccurred())
+
+				contents, err := ioutil.ReadFile(config.OpsFiles[0])
+				Expect(err).NotTo(HaveOccurred())
+				Expect(string(contents)).To(Equal("tilde-contents"))
 			})
 		})
 
@@ -253,27 +543,112 @@ var _ = Describe("Plan", func() {
 				})
 				Expect(err).NotTo(HaveOccurred())
 
-				filePath := config.OpsFile
-				fileContents, err := ioutil.ReadFile(filePath)
+				Expect(config.OpsFiles).To(Equal([]string{filepath.Join(tempDir, "user-ops-file-0.yml")}))
+
+				fileContents, err := ioutil.ReadFile(config.OpsFiles[0])
 				Expect(err).NotTo(HaveOccurred())
 
 				Expect(string(fileContents)).To(Equal("some-ops-file-contents"))
 			})
 
-			It("writes the previous user ops file to the .bbl directory", func() {
+			It("reuses state.BOSH.UserOpsFiles in order when it already has multiple entries", func() {
 				config, err := command.ParseArgs([]string{}, storage.State{
 					BOSH: storage.BOSH{
-						UserOpsFile: "some-ops-file-contents",
+						UserOpsFiles: []string{"first-contents", "second-contents"},
 					},
 				})
 				Expect(err).NotTo(HaveOccurred())
 
-				filePath := config.OpsFile
-				fileContents, err := ioutil.ReadFile(filePath)
+				Expect(config.OpsFiles).To(Equal([]string{
+					filepath.Join(tempDir, "user-ops-file-0.yml"),
+					filepath.Join(tempDir, "user-ops-file-1.yml"),
+				}))
+
+				firstContents, err := ioutil.ReadFile(config.OpsFiles[0])
 				Expect(err).NotTo(HaveOccurred())
+				Expect(string(firstContents)).To(Equal("first-contents"))
 
-				Expect(filePath).To(Equal(filepath.Join(tempDir, "previous-user-ops-file.yml")))
-				Expect(string(fileContents)).To(Equal("some-ops-file-contents"))
+				secondContents, err := ioutil.ReadFile(config.OpsFiles[1])
+				Expect(err).NotTo(HaveOccurred())
+				Expect(string(secondContents)).To(Equal("second-contents"))
+			})
+
+			It("does not write any ops files when none were ever provided", func() {
+				config, err := command.ParseArgs([]string{}, storage.State{})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(config.OpsFiles).To(BeEmpty())
+			})
+		})
+
+		Context("when the --config flag is specified", func() {
+			var configOpsFilePath string
+
+			BeforeEach(func() {
+				opsFileDir, err := ioutil.TempDir("", "")
+				Expect(err).NotTo(HaveOccurred())
+
+				configOpsFilePath = filepath.Join(opsFileDir, "config-file-ops-file")
+				Expect(ioutil.WriteFile(configOpsFilePath, []byte("config-file-contents"), os.ModePerm)).To(Succeed())
+
+				configLoader.LoadCall.Returns.File = bblconfig.PlanConfigFile{
+					Name:       "config-file-name",
+					NoDirector: true,
+					OpsFiles:   []string{configOpsFilePath},
+				}
+			})
+
+			It("loads the config file from the given path", func() {
+				_, err := command.ParseArgs([]string{"--config", "/some/bbl.yml"}, storage.State{})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(configLoader.LoadCall.CallCount).To(Equal(1))
+				Expect(configLoader.LoadCall.Receives.Path).To(Equal("/some/bbl.yml"))
+			})
+
+			It("uses the config file's values when no matching flag is provided", func() {
+				config, err := command.ParseArgs([]string{"--config", "/some/bbl.yml"}, storage.State{})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(config.Name).To(Equal("config-file-name"))
+				Expect(config.NoDirector).To(BeTrue())
+			})
+
+			Context("when a flag is also passed on the command line", func() {
+				It("prefers the command line flag over the config file", func() {
+					config, err := command.ParseArgs([]string{
+						"--config", "/some/bbl.yml",
+						"--name", "cli-name",
+					}, storage.State{})
+					Expect(err).NotTo(HaveOccurred())
+
+					Expect(config.Name).To(Equal("cli-name"))
+				})
+
+				It("prefers repeated --ops-file flags over the config file's ops_files", func() {
+					opsFileDir, err := ioutil.TempDir("", "")
+					Expect(err).NotTo(HaveOccurred())
+
+					opsFilePath := filepath.Join(opsFileDir, "cli-ops-file")
+					Expect(ioutil.WriteFile(opsFilePath, []byte("cli-ops-file-contents"), os.ModePerm)).To(Succeed())
+
+					config, err := command.ParseArgs([]string{
+						"--config", "/some/bbl.yml",
+						"--ops-file", opsFilePath,
+					}, storage.State{})
+					Expect(err).NotTo(HaveOccurred())
+
+					contents, err := ioutil.ReadFile(config.OpsFiles[0])
+					Expect(err).NotTo(HaveOccurred())
+					Expect(string(contents)).To(Equal("cli-ops-file-contents"))
+				})
+			})
+
+			It("returns an error when the config file cannot be loaded", func() {
+				configLoader.LoadCall.Returns.Error = errors.New("kumquat")
+
+				_, err := command.ParseArgs([]string{"--config", "/some/bbl.yml"}, storage.State{})
+				Expect(err).To(MatchError("Load config file: kumquat"))
 			})
 		})
 
@@ -309,6 +684,18 @@ var _ = Describe("Plan", func() {
 			})
 		})
 
+		Context("when the user provides --log-level and --log-file flags", func() {
+			It("passes them in the up config", func() {
+				config, err := command.ParseArgs([]string{
+					"--log-level", "debug",
+					"--log-file", "/some/custom.log",
+				}, storage.State{})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(config.LogLevel).To(Equal("debug"))
+				Expect(config.LogFile).To(Equal("/some/custom.log"))
+			})
+		})
+
 		Context("failure cases", func() {
 			Context("when undefined flags are passed", func() {
 				It("returns an error", func() {
@@ -0,0 +1,84 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// PlanConfigFile is the shape of a `bbl.yml` (or `.json`) file that a user
+// can check into source control in place of assembling a long
+// `bbl plan --name ... --ops-file ...` invocation.
+type PlanConfigFile struct {
+	Name          string            `yaml:"name" json:"name"`
+	NoDirector    bool              `yaml:"no_director" json:"no_director"`
+	OpsFiles      []string          `yaml:"ops_files" json:"ops_files"`
+	IAAS          string            `yaml:"iaas" json:"iaas"`
+	LB            LBConfig          `yaml:"lb" json:"lb"`
+	TerraformVars map[string]string `yaml:"terraform_vars" json:"terraform_vars"`
+}
+
+type LBConfig struct {
+	Type   string `yaml:"type" json:"type"`
+	Domain string `yaml:"domain" json:"domain"`
+	Cert   string `yaml:"cert" json:"cert"`
+	Key    string `yaml:"key" json:"key"`
+}
+
+// Loader reads a PlanConfigFile from disk, resolving any relative ops file
+// paths it contains against the config file's own directory rather than
+// the caller's working directory.
+type Loader struct{}
+
+func NewLoader() Loader {
+	return Loader{}
+}
+
+func (l Loader) Load(path string) (PlanConfigFile, error) {
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return PlanConfigFile{}, fmt.Errorf("Read config file: %s", err)
+	}
+
+	var file PlanConfigFile
+	if strings.ToLower(filepath.Ext(path)) == ".json" {
+		err = json.Unmarshal(contents, &file)
+	} else {
+		err = yaml.Unmarshal(contents, &file)
+	}
+	if err != nil {
+		return PlanConfigFile{}, fmt.Errorf("Parse config file: %s", err)
+	}
+
+	configDir := filepath.Dir(path)
+	for i, opsFile := range file.OpsFiles {
+		file.OpsFiles[i] = resolveRelativeToDir(opsFile, configDir)
+	}
+
+	return file, nil
+}
+
+// resolveRelativeToDir applies bbl's standard ops-file resolution rules,
+// rooting plain relative paths at dir (the config file's directory)
+// instead of the caller's working directory. Remote URLs, file:// URIs,
+// absolute paths, and ~-relative paths are left untouched since they carry
+// their own resolution rule, applied later wherever the ops file is
+// actually read.
+func resolveRelativeToDir(raw string, dir string) string {
+	switch {
+	case strings.HasPrefix(raw, "http"):
+		return raw
+	case strings.HasPrefix(raw, "file://"):
+		return raw
+	case filepath.IsAbs(raw):
+		return raw
+	case strings.HasPrefix(raw, "~"):
+		return raw
+	default:
+		return filepath.Join(dir, raw)
+	}
+}
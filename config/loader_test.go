@@ -0,0 +1,121 @@
+package config_test
+
+import (
+	"io/ioutil"
+	"path/filepath"
+
+	"github.com/cloudfoundry/bosh-bootloader/config"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Loader", func() {
+	var (
+		loader    config.Loader
+		configDir string
+	)
+
+	BeforeEach(func() {
+		var err error
+		configDir, err = ioutil.TempDir("", "")
+		Expect(err).NotTo(HaveOccurred())
+
+		loader = config.NewLoader()
+	})
+
+	Describe("Load", func() {
+		Context("when the file is YAML", func() {
+			It("parses the config file", func() {
+				configPath := filepath.Join(configDir, "bbl.yml")
+				contents := `
+name: some-env
+no_director: true
+iaas: gcp
+ops_files:
+- some-ops-file.yml
+lb:
+  type: cf
+  domain: some-domain.com
+terraform_vars:
+  project_id: some-project
+`
+				Expect(ioutil.WriteFile(configPath, []byte(contents), 0600)).To(Succeed())
+
+				file, err := loader.Load(configPath)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(file.Name).To(Equal("some-env"))
+				Expect(file.NoDirector).To(BeTrue())
+				Expect(file.IAAS).To(Equal("gcp"))
+				Expect(file.LB).To(Equal(config.LBConfig{Type: "cf", Domain: "some-domain.com"}))
+				Expect(file.TerraformVars).To(Equal(map[string]string{"project_id": "some-project"}))
+			})
+		})
+
+		Context("when the file is JSON", func() {
+			It("parses the config file", func() {
+				configPath := filepath.Join(configDir, "bbl.json")
+				contents := `{"name": "some-env", "ops_files": ["some-ops-file.yml"]}`
+				Expect(ioutil.WriteFile(configPath, []byte(contents), 0600)).To(Succeed())
+
+				file, err := loader.Load(configPath)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(file.Name).To(Equal("some-env"))
+			})
+		})
+
+		Context("ops file path resolution", func() {
+			It("resolves relative ops file paths against the config file's directory", func() {
+				configPath := filepath.Join(configDir, "bbl.yml")
+				contents := "ops_files:\n- some-ops-file.yml\n"
+				Expect(ioutil.WriteFile(configPath, []byte(contents), 0600)).To(Succeed())
+
+				file, err := loader.Load(configPath)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(file.OpsFiles).To(Equal([]string{filepath.Join(configDir, "some-ops-file.yml")}))
+			})
+
+			It("leaves absolute, ~-relative, file://, and http(s) ops file paths untouched", func() {
+				configPath := filepath.Join(configDir, "bbl.yml")
+				contents := `
+ops_files:
+- /absolute/ops-file.yml
+- ~/home-ops-file.yml
+- file:///absolute/file-uri-ops-file.yml
+- https://example.com/ops-file.yml
+`
+				Expect(ioutil.WriteFile(configPath, []byte(contents), 0600)).To(Succeed())
+
+				file, err := loader.Load(configPath)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(file.OpsFiles).To(Equal([]string{
+					"/absolute/ops-file.yml",
+					"~/home-ops-file.yml",
+					"file:///absolute/file-uri-ops-file.yml",
+					"https://example.com/ops-file.yml",
+				}))
+			})
+		})
+
+		Context("failure cases", func() {
+			It("returns an error when the file cannot be read", func() {
+				_, err := loader.Load(filepath.Join(configDir, "missing.yml"))
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("Read config file"))
+			})
+
+			It("returns an error when the file cannot be parsed", func() {
+				configPath := filepath.Join(configDir, "bbl.yml")
+				Expect(ioutil.WriteFile(configPath, []byte("{not valid yaml::"), 0600)).To(Succeed())
+
+				_, err := loader.Load(configPath)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("Parse config file"))
+			})
+		})
+	})
+})
@@ -0,0 +1,50 @@
+package fakes
+
+import "github.com/cloudfoundry/bosh-bootloader/storage"
+
+type BOSHManager struct {
+	VersionCall struct {
+		CallCount int
+		Returns   struct {
+			Version string
+			Error   error
+		}
+	}
+
+	InitializeJumpboxCall struct {
+		CallCount int
+		Receives  struct {
+			State storage.State
+		}
+		Returns struct {
+			Error error
+		}
+	}
+
+	InitializeDirectorCall struct {
+		CallCount int
+		Receives  struct {
+			State storage.State
+		}
+		Returns struct {
+			Error error
+		}
+	}
+}
+
+func (b *BOSHManager) Version() (string, error) {
+	b.VersionCall.CallCount++
+	return b.VersionCall.Returns.Version, b.VersionCall.Returns.Error
+}
+
+func (b *BOSHManager) InitializeJumpbox(state storage.State) error {
+	b.InitializeJumpboxCall.CallCount++
+	b.InitializeJumpboxCall.Receives.State = state
+	return b.InitializeJumpboxCall.Returns.Error
+}
+
+func (b *BOSHManager) InitializeDirector(state storage.State) error {
+	b.InitializeDirectorCall.CallCount++
+	b.InitializeDirectorCall.Receives.State = state
+	return b.InitializeDirectorCall.Returns.Error
+}
@@ -0,0 +1,21 @@
+package fakes
+
+import "github.com/cloudfoundry/bosh-bootloader/storage"
+
+type CloudConfigManager struct {
+	InitializeCall struct {
+		CallCount int
+		Receives  struct {
+			State storage.State
+		}
+		Returns struct {
+			Error error
+		}
+	}
+}
+
+func (c *CloudConfigManager) Initialize(state storage.State) error {
+	c.InitializeCall.CallCount++
+	c.InitializeCall.Receives.State = state
+	return c.InitializeCall.Returns.Error
+}
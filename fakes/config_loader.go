@@ -0,0 +1,22 @@
+package fakes
+
+import "github.com/cloudfoundry/bosh-bootloader/config"
+
+type ConfigLoader struct {
+	LoadCall struct {
+		CallCount int
+		Receives  struct {
+			Path string
+		}
+		Returns struct {
+			File  config.PlanConfigFile
+			Error error
+		}
+	}
+}
+
+func (c *ConfigLoader) Load(path string) (config.PlanConfigFile, error) {
+	c.LoadCall.CallCount++
+	c.LoadCall.Receives.Path = path
+	return c.LoadCall.Returns.File, c.LoadCall.Returns.Error
+}
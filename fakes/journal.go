@@ -0,0 +1,73 @@
+package fakes
+
+import "github.com/cloudfoundry/bosh-bootloader/storage"
+
+type JournalTaskReturn struct {
+	Task storage.PlanTask
+	Ok   bool
+}
+
+type JournalRecordReceive struct {
+	Name   string
+	Status string
+	Error  error
+	State  string
+}
+
+type Journal struct {
+	TaskCall struct {
+		CallCount int
+		Receives  []string
+		Returns   map[string]JournalTaskReturn
+	}
+
+	TasksCall struct {
+		CallCount int
+		Returns   struct {
+			Tasks []storage.PlanTask
+			Error error
+		}
+	}
+
+	RecordCall struct {
+		CallCount int
+		Receives  []JournalRecordReceive
+		Returns   []error
+	}
+}
+
+func (j *Journal) Task(name string) (storage.PlanTask, bool) {
+	j.TaskCall.Receives = append(j.TaskCall.Receives, name)
+	j.TaskCall.CallCount++
+
+	if j.TaskCall.Returns == nil {
+		return storage.PlanTask{}, false
+	}
+
+	ret, ok := j.TaskCall.Returns[name]
+	if !ok {
+		return storage.PlanTask{}, false
+	}
+	return ret.Task, ret.Ok
+}
+
+func (j *Journal) Tasks() ([]storage.PlanTask, error) {
+	j.TasksCall.CallCount++
+	return j.TasksCall.Returns.Tasks, j.TasksCall.Returns.Error
+}
+
+func (j *Journal) Record(name string, status string, recordErr error, state string) error {
+	j.RecordCall.Receives = append(j.RecordCall.Receives, JournalRecordReceive{
+		Name:   name,
+		Status: status,
+		Error:  recordErr,
+		State:  state,
+	})
+
+	var err error
+	if len(j.RecordCall.Returns) > j.RecordCall.CallCount {
+		err = j.RecordCall.Returns[j.RecordCall.CallCount]
+	}
+	j.RecordCall.CallCount++
+	return err
+}
@@ -0,0 +1,58 @@
+package fakes
+
+import "time"
+
+type LoggerStepReceive struct {
+	Name  string
+	Start time.Time
+	Error error
+}
+
+type Logger struct {
+	ConfigureCall struct {
+		CallCount int
+		Receives  struct {
+			Path  string
+			Level string
+		}
+		Returns struct {
+			Error error
+		}
+	}
+
+	SetSecretsCall struct {
+		CallCount int
+		Receives  struct {
+			Secrets []string
+		}
+	}
+
+	StepCall struct {
+		CallCount int
+		Receives  []LoggerStepReceive
+		Returns   []error
+	}
+}
+
+func (l *Logger) Configure(path string, level string) error {
+	l.ConfigureCall.CallCount++
+	l.ConfigureCall.Receives.Path = path
+	l.ConfigureCall.Receives.Level = level
+	return l.ConfigureCall.Returns.Error
+}
+
+func (l *Logger) SetSecrets(secrets []string) {
+	l.SetSecretsCall.CallCount++
+	l.SetSecretsCall.Receives.Secrets = secrets
+}
+
+func (l *Logger) Step(name string, start time.Time, stepErr error) error {
+	l.StepCall.Receives = append(l.StepCall.Receives, LoggerStepReceive{Name: name, Start: start, Error: stepErr})
+
+	var err error
+	if len(l.StepCall.Returns) > l.StepCall.CallCount {
+		err = l.StepCall.Returns[l.StepCall.CallCount]
+	}
+	l.StepCall.CallCount++
+	return err
+}
@@ -0,0 +1,44 @@
+package fakes
+
+import "github.com/cloudfoundry/bosh-bootloader/storage"
+
+type SetCallReceive struct {
+	State storage.State
+}
+
+type SetCallReturn struct {
+	Error error
+}
+
+type StateStore struct {
+	SetCall struct {
+		CallCount int
+		Receives  []SetCallReceive
+		Returns   []SetCallReturn
+	}
+
+	GetBblDirCall struct {
+		CallCount int
+		Returns   struct {
+			Directory string
+			Error     error
+		}
+	}
+}
+
+func (s *StateStore) Set(state storage.State) error {
+	s.SetCall.Receives = append(s.SetCall.Receives, SetCallReceive{State: state})
+
+	var err error
+	if len(s.SetCall.Returns) > s.SetCall.CallCount {
+		err = s.SetCall.Returns[s.SetCall.CallCount].Error
+	}
+
+	s.SetCall.CallCount++
+	return err
+}
+
+func (s *StateStore) GetBblDir() (string, error) {
+	s.GetBblDirCall.CallCount++
+	return s.GetBblDirCall.Returns.Directory, s.GetBblDirCall.Returns.Error
+}
@@ -0,0 +1,33 @@
+package fakes
+
+import "github.com/cloudfoundry/bosh-bootloader/storage"
+
+type TerraformManager struct {
+	InitCall struct {
+		CallCount int
+		Receives  struct {
+			BBLState storage.State
+		}
+		Returns struct {
+			Error error
+		}
+	}
+
+	ValidateVersionCall struct {
+		CallCount int
+		Returns   struct {
+			Error error
+		}
+	}
+}
+
+func (t *TerraformManager) Init(state storage.State) error {
+	t.InitCall.CallCount++
+	t.InitCall.Receives.BBLState = state
+	return t.InitCall.Returns.Error
+}
+
+func (t *TerraformManager) ValidateVersion() error {
+	t.ValidateVersionCall.CallCount++
+	return t.ValidateVersionCall.Returns.Error
+}
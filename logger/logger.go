@@ -0,0 +1,140 @@
+package logger
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	LevelDebug = "debug"
+	LevelInfo  = "info"
+	LevelWarn  = "warn"
+	LevelError = "error"
+
+	// DefaultMaxBytes is the size a log file is allowed to grow to before
+	// it is rotated to a ".1" suffixed file on the next write.
+	DefaultMaxBytes = 10 * 1024 * 1024
+
+	logFileMode = 0600
+)
+
+var levelRank = map[string]int{
+	LevelDebug: 0,
+	LevelInfo:  1,
+	LevelWarn:  2,
+	LevelError: 3,
+}
+
+func ValidLevel(level string) bool {
+	_, ok := levelRank[level]
+	return ok
+}
+
+// Logger is a small structured audit logger that appends one line per
+// logged step to a file, redacting any configured secrets before they hit
+// disk, and rotating the file once it reaches DefaultMaxBytes.
+type Logger struct {
+	path    string
+	level   string
+	secrets []string
+}
+
+func New() *Logger {
+	return &Logger{level: LevelInfo}
+}
+
+// Configure sets the destination file and minimum level for subsequent
+// Step calls. An empty level defaults to "info".
+func (l *Logger) Configure(path string, level string) error {
+	if level == "" {
+		level = LevelInfo
+	}
+
+	if !ValidLevel(level) {
+		return fmt.Errorf("invalid log level %q", level)
+	}
+
+	l.path = path
+	l.level = level
+	return nil
+}
+
+// SetSecrets registers values (e.g. director credentials) that must be
+// redacted from any line written to the log file.
+func (l *Logger) SetSecrets(secrets []string) {
+	l.secrets = secrets
+}
+
+// Step records a single structured line for a completed step: timestamp,
+// step name, duration, outcome, and, on failure, the (redacted) error.
+func (l *Logger) Step(name string, start time.Time, stepErr error) error {
+	level := LevelInfo
+	outcome := "ok"
+	if stepErr != nil {
+		level = LevelError
+		outcome = "error"
+	}
+
+	if levelRank[level] < levelRank[l.level] {
+		return nil
+	}
+
+	line := fmt.Sprintf("%s\tstep=%s\tduration=%s\toutcome=%s",
+		time.Now().UTC().Format(time.RFC3339),
+		name,
+		time.Since(start),
+		outcome,
+	)
+
+	if stepErr != nil {
+		line += fmt.Sprintf("\terror=%s", l.redact(stepErr.Error()))
+	}
+
+	return l.write(line + "\n")
+}
+
+func (l *Logger) redact(message string) string {
+	for _, secret := range l.secrets {
+		if secret == "" {
+			continue
+		}
+		message = strings.Replace(message, secret, "REDACTED", -1)
+	}
+	return message
+}
+
+func (l *Logger) write(line string) error {
+	if err := l.rotateIfNeeded(); err != nil {
+		return fmt.Errorf("Rotate log file: %s", err)
+	}
+
+	file, err := os.OpenFile(l.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, logFileMode)
+	if err != nil {
+		return fmt.Errorf("Open log file: %s", err)
+	}
+	defer file.Close()
+
+	if _, err := file.WriteString(line); err != nil {
+		return fmt.Errorf("Write log file: %s", err)
+	}
+
+	return nil
+}
+
+func (l *Logger) rotateIfNeeded() error {
+	info, err := os.Stat(l.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	if info.Size() < DefaultMaxBytes {
+		return nil
+	}
+
+	return os.Rename(l.path, l.path+".1")
+}
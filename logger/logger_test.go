@@ -0,0 +1,134 @@
+package logger_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/cloudfoundry/bosh-bootloader/logger"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Logger", func() {
+	var (
+		log     *logger.Logger
+		logPath string
+	)
+
+	BeforeEach(func() {
+		logDir, err := ioutil.TempDir("", "")
+		Expect(err).NotTo(HaveOccurred())
+
+		logPath = filepath.Join(logDir, "bbl.log")
+		log = logger.New()
+		Expect(log.Configure(logPath, logger.LevelInfo)).To(Succeed())
+	})
+
+	Describe("Configure", func() {
+		It("rejects an invalid log level", func() {
+			err := log.Configure(logPath, "extremely-verbose")
+			Expect(err).To(MatchError(`invalid log level "extremely-verbose"`))
+		})
+
+		It("defaults to info when no level is given", func() {
+			Expect(log.Configure(logPath, "")).To(Succeed())
+			Expect(log.Step("some-step", time.Now(), errors.New("boom"))).To(Succeed())
+
+			contents, err := ioutil.ReadFile(logPath)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(contents)).To(ContainSubstring("outcome=error"))
+		})
+	})
+
+	Describe("Step", func() {
+		It("creates the log file with 0600 permissions", func() {
+			Expect(log.Step("sync-env-id", time.Now(), nil)).To(Succeed())
+
+			info, err := os.Stat(logPath)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(info.Mode().Perm()).To(Equal(os.FileMode(0600)))
+		})
+
+		It("appends a structured line with step, duration, and outcome", func() {
+			start := time.Now().Add(-time.Second)
+			Expect(log.Step("terraform-init", start, nil)).To(Succeed())
+
+			contents, err := ioutil.ReadFile(logPath)
+			Expect(err).NotTo(HaveOccurred())
+
+			line := string(contents)
+			Expect(line).To(ContainSubstring("step=terraform-init"))
+			Expect(line).To(ContainSubstring("outcome=ok"))
+			Expect(line).To(ContainSubstring("duration="))
+		})
+
+		It("includes the error message when the step failed", func() {
+			Expect(log.Step("cloud-config-init", time.Now(), errors.New("potato"))).To(Succeed())
+
+			contents, err := ioutil.ReadFile(logPath)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(contents)).To(ContainSubstring("error=potato"))
+		})
+
+		It("appends rather than truncates across multiple steps", func() {
+			Expect(log.Step("step-one", time.Now(), nil)).To(Succeed())
+			Expect(log.Step("step-two", time.Now(), nil)).To(Succeed())
+
+			contents, err := ioutil.ReadFile(logPath)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(strings.Count(string(contents), "\n")).To(Equal(2))
+		})
+
+		Context("when the configured level is above the step's level", func() {
+			It("does not write a line for successful steps", func() {
+				Expect(log.Configure(logPath, logger.LevelWarn)).To(Succeed())
+				Expect(log.Step("sync-env-id", time.Now(), nil)).To(Succeed())
+
+				_, err := ioutil.ReadFile(logPath)
+				Expect(err).To(HaveOccurred())
+			})
+
+			It("still writes a line for failed steps", func() {
+				Expect(log.Configure(logPath, logger.LevelWarn)).To(Succeed())
+				Expect(log.Step("sync-env-id", time.Now(), errors.New("boom"))).To(Succeed())
+
+				contents, err := ioutil.ReadFile(logPath)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(string(contents)).To(ContainSubstring("outcome=error"))
+			})
+		})
+
+		Context("when secrets have been configured", func() {
+			It("redacts them from the error message before writing", func() {
+				log.SetSecrets([]string{"super-secret-password"})
+
+				err := log.Step("initialize-director", time.Now(), errors.New("auth failed with password super-secret-password"))
+				Expect(err).NotTo(HaveOccurred())
+
+				contents, err := ioutil.ReadFile(logPath)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(string(contents)).NotTo(ContainSubstring("super-secret-password"))
+				Expect(string(contents)).To(ContainSubstring("REDACTED"))
+			})
+		})
+
+		Context("when the log file has reached the rotation threshold", func() {
+			It("rotates the existing file to a .1 suffix before writing", func() {
+				Expect(ioutil.WriteFile(logPath, make([]byte, logger.DefaultMaxBytes), 0600)).To(Succeed())
+
+				Expect(log.Step("sync-env-id", time.Now(), nil)).To(Succeed())
+
+				Expect(logPath + ".1").To(BeAnExistingFile())
+
+				contents, err := ioutil.ReadFile(logPath)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(string(contents)).To(ContainSubstring("step=sync-env-id"))
+			})
+		})
+	})
+})
@@ -0,0 +1,130 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	PlanTaskStatusPending = "pending"
+	PlanTaskStatusRunning = "running"
+	PlanTaskStatusDone    = "done"
+	PlanTaskStatusFailed  = "failed"
+)
+
+// PlanTask is a single step of a `bbl plan` run, as recorded in the plan
+// journal.
+type PlanTask struct {
+	Name      string `json:"name"`
+	Status    string `json:"status"`
+	UpdatedAt string `json:"updated_at"`
+	Error     string `json:"error,omitempty"`
+
+	// State holds a JSON-encoded snapshot of any state this step produced,
+	// for steps whose result must be restored on skip rather than
+	// re-derived (e.g. a synced env ID that hasn't been durably saved yet).
+	State string `json:"state,omitempty"`
+}
+
+// PlanJournal is a task journal, analogous to the bosh-agent task manager,
+// that records the state of each step of `bbl plan` in
+// <bblDir>/plan-tasks.json so that a plan interrupted partway through can
+// be resumed without re-running steps that already completed.
+type PlanJournal struct {
+	path string
+}
+
+func NewPlanJournal(bblDir string) PlanJournal {
+	return PlanJournal{path: filepath.Join(bblDir, "plan-tasks.json")}
+}
+
+func (j PlanJournal) Task(name string) (PlanTask, bool) {
+	tasks, err := j.load()
+	if err != nil {
+		return PlanTask{}, false
+	}
+
+	for _, task := range tasks {
+		if task.Name == name {
+			return task, true
+		}
+	}
+
+	return PlanTask{}, false
+}
+
+func (j PlanJournal) Tasks() ([]PlanTask, error) {
+	return j.load()
+}
+
+// Record updates name's status in the journal. state is an optional
+// JSON-encoded snapshot of the step's result, persisted alongside the
+// status so that a later skip can restore it rather than re-derive it; pass
+// "" for steps that don't need this.
+func (j PlanJournal) Record(name string, status string, recordErr error, state string) error {
+	tasks, err := j.load()
+	if err != nil {
+		return err
+	}
+
+	message := ""
+	if recordErr != nil {
+		message = recordErr.Error()
+	}
+
+	updated := PlanTask{
+		Name:      name,
+		Status:    status,
+		UpdatedAt: time.Now().UTC().Format(time.RFC3339),
+		Error:     message,
+		State:     state,
+	}
+
+	found := false
+	for i, task := range tasks {
+		if task.Name == name {
+			tasks[i] = updated
+			found = true
+			break
+		}
+	}
+	if !found {
+		tasks = append(tasks, updated)
+	}
+
+	return j.save(tasks)
+}
+
+func (j PlanJournal) load() ([]PlanTask, error) {
+	contents, err := ioutil.ReadFile(j.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []PlanTask{}, nil
+		}
+		return nil, fmt.Errorf("Read plan journal: %s", err)
+	}
+
+	tasks := []PlanTask{}
+	if err := json.Unmarshal(contents, &tasks); err != nil {
+		return nil, fmt.Errorf("Unmarshal plan journal: %s", err)
+	}
+
+	return tasks, nil
+}
+
+func (j PlanJournal) save(tasks []PlanTask) error {
+	contents, err := json.Marshal(tasks)
+	if err != nil {
+		return fmt.Errorf("Marshal plan journal: %s", err)
+	}
+
+	if err := ioutil.WriteFile(j.path, contents, StateMode); err != nil {
+		return fmt.Errorf("Write plan journal: %s", err)
+	}
+
+	return nil
+}
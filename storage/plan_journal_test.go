@@ -0,0 +1,87 @@
+package storage_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+
+	"github.com/cloudfoundry/bosh-bootloader/storage"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("PlanJournal", func() {
+	var (
+		journal storage.PlanJournal
+		bblDir  string
+	)
+
+	BeforeEach(func() {
+		var err error
+		bblDir, err = ioutil.TempDir("", "")
+		Expect(err).NotTo(HaveOccurred())
+
+		journal = storage.NewPlanJournal(bblDir)
+	})
+
+	Describe("Record and Task", func() {
+		It("persists a task's status to plan-tasks.json in the bbl dir", func() {
+			err := journal.Record("sync-env-id", storage.PlanTaskStatusRunning, nil, "")
+			Expect(err).NotTo(HaveOccurred())
+
+			task, ok := journal.Task("sync-env-id")
+			Expect(ok).To(BeTrue())
+			Expect(task.Status).To(Equal(storage.PlanTaskStatusRunning))
+
+			Expect(filepath.Join(bblDir, "plan-tasks.json")).To(BeAnExistingFile())
+		})
+
+		It("overwrites a task's previous status when recorded again", func() {
+			Expect(journal.Record("save-state", storage.PlanTaskStatusRunning, nil, "")).To(Succeed())
+			Expect(journal.Record("save-state", storage.PlanTaskStatusDone, nil, "")).To(Succeed())
+
+			task, ok := journal.Task("save-state")
+			Expect(ok).To(BeTrue())
+			Expect(task.Status).To(Equal(storage.PlanTaskStatusDone))
+		})
+
+		It("records the error message when a task fails", func() {
+			Expect(journal.Record("terraform-init", storage.PlanTaskStatusFailed, errors.New("pomegranate"), "")).To(Succeed())
+
+			task, ok := journal.Task("terraform-init")
+			Expect(ok).To(BeTrue())
+			Expect(task.Error).To(Equal("pomegranate"))
+		})
+
+		It("returns false when the task has never been recorded", func() {
+			_, ok := journal.Task("never-run")
+			Expect(ok).To(BeFalse())
+		})
+
+		It("persists the optional state snapshot alongside the status", func() {
+			Expect(journal.Record("sync-env-id", storage.PlanTaskStatusDone, nil, `{"EnvID":"some-env-id"}`)).To(Succeed())
+
+			task, ok := journal.Task("sync-env-id")
+			Expect(ok).To(BeTrue())
+			Expect(task.State).To(Equal(`{"EnvID":"some-env-id"}`))
+		})
+	})
+
+	Describe("Tasks", func() {
+		It("returns every recorded task", func() {
+			Expect(journal.Record("sync-env-id", storage.PlanTaskStatusDone, nil, "")).To(Succeed())
+			Expect(journal.Record("save-state", storage.PlanTaskStatusRunning, nil, "")).To(Succeed())
+
+			tasks, err := journal.Tasks()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(tasks).To(HaveLen(2))
+		})
+
+		It("returns an empty list when no tasks have been recorded", func() {
+			tasks, err := journal.Tasks()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(tasks).To(BeEmpty())
+		})
+	})
+})
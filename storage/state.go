@@ -0,0 +1,50 @@
+package storage
+
+// StateMode is the file permission bbl uses for files it writes into the
+// bbl state directory that may contain sensitive material (ops files,
+// director credentials).
+const StateMode = 0600
+
+type State struct {
+	ID         string
+	EnvID      string
+	IAAS       string
+	Version    int
+	NoDirector bool
+	BOSH       BOSH
+	LB         LB
+
+	// TFVars holds arbitrary Terraform input variables, keyed by variable
+	// name, to pass through to the terraform manager.
+	TFVars map[string]string
+}
+
+// LB holds the load balancer settings a user configured, either via
+// `bbl plan` flags or a `bbl.yml` config file's `lb` block.
+type LB struct {
+	Type   string
+	Domain string
+	Cert   string
+	Key    string
+}
+
+type BOSH struct {
+	DirectorUsername string
+	DirectorPassword string
+
+	DirectorSSLCA          string
+	DirectorSSLCertificate string
+	DirectorSSLPrivateKey  string
+
+	// UserOpsFile holds the contents of the single ops file a user provided
+	// via the original `--ops-file` flag.
+	//
+	// Deprecated: retained only so state written before UserOpsFiles existed
+	// can be migrated; new state is written to UserOpsFiles.
+	UserOpsFile string
+
+	// UserOpsFiles holds the contents of each ops file a user provided via
+	// `--ops-file`, in the order they were passed, so that re-running
+	// `bbl plan` without flags reuses them in the same order.
+	UserOpsFiles []string
+}